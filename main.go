@@ -1,44 +1,79 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"math"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 
 	"github.com/gordonklaus/portaudio"
-	"github.com/mjibson/go-dsp/fft"
+	"github.com/rakyll/portmidi"
+
+	"github.com/Kazzyman/grockMusic/midiout"
+	"github.com/Kazzyman/grockMusic/pitchdetect"
+	"github.com/Kazzyman/grockMusic/spectrum"
+	"github.com/Kazzyman/grockMusic/transcribe"
 )
 
 const (
-	sampleRate      = 44100 // CD-quality sample rate
-	framesPerBuffer = 2048  // Increased for better resolution
+	sampleRate      = 44100               // CD-quality sample rate
+	framesPerBuffer = 2048                // Increased for better resolution
+	hopSize         = framesPerBuffer / 2 // 50% overlap between analysis frames
 )
 
-type Note struct {
-	Name      string
-	Frequency float64
-}
+func main() {
+	midiOutFlag := flag.String("midi-out", "", "send detected notes as MIDI to this PortMidi device ID (use -midi-out=list to enumerate devices)")
+	fileFlag := flag.String("file", "", "transcribe a WAV or FLAC file instead of listening live")
+	outFlag := flag.String("out", "", "write -file transcription results here (.csv or .mid); defaults to stdout")
+	algoFlag := flag.String("algo", "fft", "pitch-detection backend for live listening: fft or yin")
+	flag.Parse()
+
+	if *algoFlag != "fft" && *algoFlag != "yin" {
+		fmt.Printf("Error: -algo must be \"fft\" or \"yin\", got %q\n", *algoFlag)
+		return
+	}
+
+	if *fileFlag != "" {
+		if err := transcribeFile(*fileFlag, *outFlag); err != nil {
+			fmt.Printf("Error transcribing %s: %v\n", *fileFlag, err)
+		}
+		return
+	}
 
-var pianoNotes = generatePianoNotes()
+	if *midiOutFlag == "list" {
+		if err := portmidi.Initialize(); err != nil {
+			fmt.Printf("Error initializing PortMidi: %v\n", err)
+			return
+		}
+		defer portmidi.Terminate()
+		fmt.Println("Available MIDI output devices:")
+		midiout.ListDevices()
+		return
+	}
 
-func generatePianoNotes() []Note {
-	notes := make([]Note, 61)
-	noteNames := []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
-	A4 := 440.0
-	startFreq := A4 * math.Pow(2, -33.0/12.0)
+	var midiOutput *midiout.Output
+	if *midiOutFlag != "" {
+		deviceID, err := strconv.Atoi(*midiOutFlag)
+		if err != nil {
+			fmt.Printf("Error: -midi-out must be a device ID or \"list\": %v\n", err)
+			return
+		}
+		if err := portmidi.Initialize(); err != nil {
+			fmt.Printf("Error initializing PortMidi: %v\n", err)
+			return
+		}
+		defer portmidi.Terminate()
 
-	for i := 0; i < 61; i++ {
-		freq := startFreq * math.Pow(2, float64(i)/12.0)
-		octave := 2 + (i / 12)
-		noteIdx := i % 12
-		name := fmt.Sprintf("%s%d", noteNames[noteIdx], octave)
-		notes[i] = Note{Name: name, Frequency: freq}
+		midiOutput, err = midiout.Open(deviceID)
+		if err != nil {
+			fmt.Printf("Error opening MIDI output: %v\n", err)
+			return
+		}
+		defer midiOutput.Close()
 	}
-	return notes
-}
 
-func main() {
 	err := portaudio.Initialize()
 	if err != nil {
 		fmt.Printf("Error initializing PortAudio: %v\n", err)
@@ -46,12 +81,14 @@ func main() {
 	}
 	defer portaudio.Terminate()
 
+	analyzer := spectrum.NewAnalyzer(framesPerBuffer, hopSize, spectrum.Hann)
+
 	stream, err := portaudio.OpenDefaultStream(
-		1,               // input channels (mono)
-		0,               // output channels
-		sampleRate,      // sample rate
-		framesPerBuffer, // frames per buffer
-		processAudio,    // callback function
+		1,          // input channels (mono)
+		0,          // output channels
+		sampleRate, // sample rate
+		framesPerBuffer,
+		func(in []float32) { processAudio(in, analyzer, midiOutput, *algoFlag) }, // callback function
 	)
 	if err != nil {
 		fmt.Printf("Error opening stream: %v\n", err)
@@ -77,56 +114,79 @@ func main() {
 	fmt.Println("\nStopped listening.")
 }
 
-func processAudio(in []float32) {
-	samples := make([]complex128, framesPerBuffer)
-	for i, sample := range in {
-		samples[i] = complex(float64(sample), 0)
+// transcribeFile decodes an audio file, resamples it to sampleRate if
+// needed, runs the detection pipeline over it in framesPerBuffer-sized
+// frames that hop framesPerBuffer/2 samples apart, and writes the results
+// to outPath (or prints them if outPath is empty).
+func transcribeFile(path, outPath string) error {
+	samples, fileRate, err := transcribe.Decode(path)
+	if err != nil {
+		return err
 	}
+	samples = transcribe.Resample(samples, fileRate, sampleRate)
 
-	freqDomain := fft.FFT(samples)
-	magnitudes := make([]float64, framesPerBuffer/2)
-	for i := 0; i < framesPerBuffer/2; i++ {
-		magnitudes[i] = math.Sqrt(real(freqDomain[i])*real(freqDomain[i]) + imag(freqDomain[i])*imag(freqDomain[i]))
-	}
+	events := transcribe.Transcribe(samples, sampleRate, framesPerBuffer)
 
-	maxMag := 0.0
-	maxIdx := 0
-	freqResolution := float64(sampleRate) / float64(framesPerBuffer) // ~21.53 Hz
-	highFreqLimit := int(2200.0 / freqResolution)                    // ~102 bins, up to ~2200 Hz
-	for i := 0; i < highFreqLimit && i < len(magnitudes); i++ {
-		if magnitudes[i] > maxMag {
-			maxMag = magnitudes[i]
-			maxIdx = i
+	if outPath == "" {
+		for _, ev := range events {
+			seconds := float64(ev.FrameOffset) / float64(sampleRate)
+			names := make([]string, len(ev.Peaks))
+			for i, peak := range ev.Peaks {
+				names[i] = pitchdetect.FindClosestNote(peak.Frequency).Name
+			}
+			fmt.Printf("%.3fs: %s\n", seconds, strings.Join(names, " + "))
 		}
+		return nil
 	}
 
-	freq := float64(maxIdx) * freqResolution
-	closestNote := findClosestNote(freq)
-
-	// Harmonic correction
-	if maxMag > 0.05 {
-		adjustedFreq := freq
-		if freq > 261.0 { // Above C4, check for harmonics
-			fundamental := freq / 2.0
-			if math.Abs(fundamental-findClosestNote(fundamental).Frequency) < freqResolution {
-				adjustedFreq = fundamental
-				closestNote = findClosestNote(adjustedFreq)
+	return transcribe.WriteResults(outPath, events, sampleRate)
+}
+
+// processAudio detects pitch in one callback's worth of samples using
+// algo ("fft" or "yin"). The yin backend falls back to the fft backend
+// (via analyzer) whenever it can't find a lag that satisfies its
+// threshold, e.g. on silence.
+func processAudio(in []float32, analyzer *spectrum.Analyzer, midiOutput *midiout.Output, algo string) {
+	if algo == "yin" {
+		samples := make([]float64, len(in))
+		for i, s := range in {
+			samples[i] = float64(s)
+		}
+		if freq, clarity, ok := pitchdetect.DetectPitchYIN(samples, sampleRate); ok {
+			note := pitchdetect.FindClosestNote(freq)
+			peak := pitchdetect.RefinedPeak{Frequency: freq, SNR: clarity * 10}
+			if midiOutput != nil {
+				midiOutput.Update([]pitchdetect.RefinedPeak{peak})
 			}
+			fmt.Printf("\rDetected: %s (%.2f Hz, YIN)    ", note.Name, freq)
+			return
 		}
-		fmt.Printf("\rDetected: %s (%.2f Hz) | Raw Freq: %.2f Hz | Magnitude: %.4f    ",
-			closestNote.Name, closestNote.Frequency, freq, maxMag)
 	}
+
+	analyzer.Write(in, func(magnitudes []float64, _ int) {
+		handleFrame(magnitudes, midiOutput)
+	})
 }
 
-func findClosestNote(freq float64) Note {
-	closest := pianoNotes[0]
-	minDiff := math.Abs(freq - closest.Frequency)
-	for _, note := range pianoNotes[1:] {
-		diff := math.Abs(freq - note.Frequency)
-		if diff < minDiff {
-			minDiff = diff
-			closest = note
-		}
+// handleFrame detects notes in one analysis frame's magnitude spectrum,
+// advances midiOutput's note-lifecycle tracking if midiOutput is non-nil,
+// and prints what was detected.
+func handleFrame(magnitudes []float64, midiOutput *midiout.Output) {
+	freqResolution := float64(sampleRate) / float64(framesPerBuffer) // ~21.53 Hz
+	highFreqLimit := int(2200.0 / freqResolution)                    // ~102 bins, up to ~2200 Hz
+
+	peaks := pitchdetect.RefineNotes(magnitudes, freqResolution, highFreqLimit)
+	if midiOutput != nil {
+		midiOutput.Update(peaks)
+	}
+	if len(peaks) == 0 {
+		return
+	}
+
+	names := make([]string, len(peaks))
+	for i, peak := range peaks {
+		note := pitchdetect.FindClosestNote(peak.Frequency)
+		names[i] = fmt.Sprintf("%s (%.2f Hz, %+.0fc, SNR %.1f)", note.Name, peak.Frequency, peak.Cents, peak.SNR)
 	}
-	return closest
+	fmt.Printf("\rDetected: %s    ", strings.Join(names, " + "))
 }
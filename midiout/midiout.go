@@ -0,0 +1,138 @@
+// Package midiout drives a PortMidi output stream from detected pitches,
+// tracking note lifecycles so that stable pitches become MIDI Note On /
+// Note Off pairs instead of firing on every detection frame.
+package midiout
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rakyll/portmidi"
+
+	"github.com/Kazzyman/grockMusic/pitchdetect"
+)
+
+// stableFrames is how many consecutive frames a pitch must be detected
+// before a Note On is sent, so a single-frame spectral glitch doesn't
+// trigger a note.
+const stableFrames = 3
+
+const (
+	statusNoteOn  = 0x90
+	statusNoteOff = 0x80
+)
+
+// ListDevices prints every PortMidi device with output capability,
+// alongside the device ID that -midi-out expects.
+func ListDevices() {
+	count := portmidi.CountDevices()
+	for i := 0; i < count; i++ {
+		info := portmidi.Info(portmidi.DeviceID(i))
+		if info != nil && info.IsOutputAvailable {
+			fmt.Printf("  [%d] %s\n", i, info.Name)
+		}
+	}
+}
+
+// activeNote tracks how many consecutive frames a MIDI note number has
+// been detected, and whether a Note On has actually been sent for it yet.
+type activeNote struct {
+	streak   int
+	velocity int64
+	sounding bool
+}
+
+// midiStream is the subset of *portmidi.Stream that Output needs, so tests
+// can drive Update against a fake stream instead of a real PortMidi device.
+type midiStream interface {
+	WriteShort(status, data1, data2 int64) error
+	Close() error
+}
+
+// Output owns an open PortMidi output stream and the lifecycle state of
+// the notes currently sounding through it.
+type Output struct {
+	stream midiStream
+	active map[int]*activeNote // MIDI note number -> tracking state
+}
+
+// Open opens the PortMidi output device identified by deviceID.
+func Open(deviceID int) (*Output, error) {
+	stream, err := portmidi.NewOutputStream(portmidi.DeviceID(deviceID), 1024, 0)
+	if err != nil {
+		return nil, fmt.Errorf("opening MIDI output device %d: %w", deviceID, err)
+	}
+	return &Output{stream: stream, active: make(map[int]*activeNote)}, nil
+}
+
+// Close sends Note Off for any still-sounding notes and closes the stream.
+func (o *Output) Close() {
+	for midiNote, note := range o.active {
+		if note.sounding {
+			o.noteOff(midiNote)
+		}
+	}
+	o.stream.Close()
+}
+
+// Update advances note-lifecycle tracking by one detection frame: a pitch
+// seen stableFrames times in a row gets a Note On with velocity derived
+// from its SNR, and a sounding note that drops out of peaks gets a Note
+// Off.
+func (o *Output) Update(peaks []pitchdetect.RefinedPeak) {
+	seen := make(map[int]bool, len(peaks))
+	for _, peak := range peaks {
+		note := pitchdetect.FindClosestNote(peak.Frequency)
+		midiNote := FrequencyToMIDI(note.Frequency)
+		seen[midiNote] = true
+
+		tracked, ok := o.active[midiNote]
+		if !ok {
+			tracked = &activeNote{}
+			o.active[midiNote] = tracked
+		}
+		tracked.streak++
+		tracked.velocity = VelocityFromSNR(peak.SNR)
+
+		if !tracked.sounding && tracked.streak >= stableFrames {
+			o.noteOn(midiNote, tracked.velocity)
+			tracked.sounding = true
+		}
+	}
+
+	for midiNote, tracked := range o.active {
+		if seen[midiNote] {
+			continue
+		}
+		if tracked.sounding {
+			o.noteOff(midiNote)
+		}
+		delete(o.active, midiNote)
+	}
+}
+
+func (o *Output) noteOn(midiNote int, velocity int64) {
+	o.stream.WriteShort(statusNoteOn, int64(midiNote), velocity)
+}
+
+func (o *Output) noteOff(midiNote int) {
+	o.stream.WriteShort(statusNoteOff, int64(midiNote), 0)
+}
+
+// FrequencyToMIDI converts a frequency in Hz to the nearest MIDI note
+// number, using A4 = 440Hz = note 69.
+func FrequencyToMIDI(freq float64) int {
+	return int(math.Round(69 + 12*math.Log2(freq/440.0)))
+}
+
+// VelocityFromSNR maps a refined peak's SNR to a MIDI velocity in [1, 127].
+func VelocityFromSNR(snr float64) int64 {
+	v := int64(snr * 20)
+	if v < 1 {
+		v = 1
+	}
+	if v > 127 {
+		v = 127
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package midiout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kazzyman/grockMusic/pitchdetect"
+)
+
+// recordingStream is a midiStream that records the (status, data1, data2)
+// triples Update writes to it, so a test can assert the exact Note On /
+// Note Off sequence without a real PortMidi device.
+type recordingStream struct {
+	writes [][3]int64
+}
+
+func (r *recordingStream) WriteShort(status, data1, data2 int64) error {
+	r.writes = append(r.writes, [3]int64{status, data1, data2})
+	return nil
+}
+
+func (r *recordingStream) Close() error { return nil }
+
+func peakAt(freq, snr float64) []pitchdetect.RefinedPeak {
+	return []pitchdetect.RefinedPeak{{Frequency: freq, SNR: snr}}
+}
+
+func TestOutput_Update_AttackSustainRelease(t *testing.T) {
+	stream := &recordingStream{}
+	o := &Output{stream: stream, active: make(map[int]*activeNote)}
+
+	const freq = 440.0 // A4, MIDI note 69
+	const snr = 5.0
+	velocity := VelocityFromSNR(snr)
+
+	// Attack: stableFrames-1 frames of detection must not yet fire a Note On.
+	for i := 0; i < stableFrames-1; i++ {
+		o.Update(peakAt(freq, snr))
+	}
+	if len(stream.writes) != 0 {
+		t.Fatalf("expected no MIDI writes before %d stable frames, got %v", stableFrames, stream.writes)
+	}
+
+	// The stableFrames-th consecutive frame should trigger exactly one Note On.
+	o.Update(peakAt(freq, snr))
+	want := [][3]int64{{statusNoteOn, 69, velocity}}
+	if !reflect.DeepEqual(stream.writes, want) {
+		t.Fatalf("after reaching stableFrames, writes = %v, want %v", stream.writes, want)
+	}
+
+	// Sustain: further frames of the same pitch must not re-fire Note On.
+	for i := 0; i < 5; i++ {
+		o.Update(peakAt(freq, snr))
+	}
+	if !reflect.DeepEqual(stream.writes, want) {
+		t.Fatalf("sustain frames re-triggered MIDI writes: %v, want %v", stream.writes, want)
+	}
+
+	// Release: the pitch drops out of peaks, so Update must send exactly one Note Off.
+	o.Update(nil)
+	want = append(want, [3]int64{statusNoteOff, 69, 0})
+	if !reflect.DeepEqual(stream.writes, want) {
+		t.Fatalf("after release, writes = %v, want %v", stream.writes, want)
+	}
+
+	if _, tracked := o.active[69]; tracked {
+		t.Errorf("expected note 69 to be forgotten after release, still tracked")
+	}
+
+	// A further silent frame must not send a second Note Off.
+	o.Update(nil)
+	if !reflect.DeepEqual(stream.writes, want) {
+		t.Fatalf("silent frame after release re-sent MIDI writes: %v, want %v", stream.writes, want)
+	}
+}
+
+func TestOutput_Update_DropsBeforeStable(t *testing.T) {
+	stream := &recordingStream{}
+	o := &Output{stream: stream, active: make(map[int]*activeNote)}
+
+	// A pitch detected for fewer than stableFrames frames, then dropped,
+	// must never produce a Note On or Note Off - it was never sounding.
+	o.Update(peakAt(440.0, 5.0))
+	o.Update(nil)
+
+	if len(stream.writes) != 0 {
+		t.Errorf("expected no MIDI writes for an unstable, dropped pitch, got %v", stream.writes)
+	}
+	if len(o.active) != 0 {
+		t.Errorf("expected no tracked notes after the pitch dropped out, got %v", o.active)
+	}
+}
@@ -0,0 +1,124 @@
+package pitchdetect
+
+import "math"
+
+// numHarmonics is R, the number of harmonics multiplied together when
+// computing the Harmonic Product Spectrum.
+const numHarmonics = 5
+
+// noiseFloorStdDevs is how many standard deviations above the HPS mean a
+// peak must clear before it is reported as a note.
+const noiseFloorStdDevs = 2.0
+
+// HarmonicProductSpectrum computes HPS[k] = M[k] * M[2k] * ... * M[Rk] by
+// down-sampling magnitudes harmonics-1 times and multiplying each
+// down-sampled copy into the result in place. Bins whose harmonic index
+// runs past the end of magnitudes are zeroed, since no further harmonics
+// are observable there.
+func HarmonicProductSpectrum(magnitudes []float64, harmonics int) []float64 {
+	hps := make([]float64, len(magnitudes))
+	copy(hps, magnitudes)
+	for r := 2; r <= harmonics; r++ {
+		for k := range hps {
+			downIdx := k * r
+			if downIdx >= len(magnitudes) {
+				hps[k] = 0
+				continue
+			}
+			hps[k] *= magnitudes[downIdx]
+		}
+	}
+	return hps
+}
+
+// DetectNotes returns one Note per fundamental found in magnitudes[:maxBin].
+// Candidates are local maxima of the raw magnitude spectrum that clear an
+// adaptive noise floor - a real fundamental must carry its own energy, not
+// merely inherit it the way a subharmonic bin k can borrow an unrelated,
+// louder bin r*k's magnitude when its Harmonic Product Spectrum value is
+// computed. The Harmonic Product Spectrum is then used the other way
+// around: to collapse a note's own overtones into its fundamental, so a
+// harmonic-rich tone or a chord is reported as one Note per fundamental
+// rather than one per partial.
+func DetectNotes(magnitudes []float64, freqResolution float64, maxBin int) []Note {
+	if maxBin > len(magnitudes) {
+		maxBin = len(magnitudes)
+	}
+	spectrum := magnitudes[:maxBin]
+	hps := HarmonicProductSpectrum(spectrum, numHarmonics)
+
+	var notes []Note
+	var fundamentals []int
+	for _, k := range magnitudePeakBins(spectrum) {
+		if explainedByHarmonic(k, fundamentals, hps) {
+			continue
+		}
+		fundamentals = append(fundamentals, k)
+
+		freq := float64(k) * freqResolution
+		note := FindClosestNote(freq)
+		note.Magnitude = spectrum[k]
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// magnitudePeakBins returns, in ascending bin order, every local maximum of
+// spectrum that clears an adaptive noise floor of mean + noiseFloorStdDevs
+// * stddev. Gating on the raw spectrum rather than the Harmonic Product
+// Spectrum matters: a pure tone with no real harmonics of its own produces
+// a vanishingly small HPS value at its own bin, while a bin that merely
+// shares a factor with a louder, unrelated peak can produce a large one -
+// so an HPS-only floor both misses real notes and reports ghosts.
+func magnitudePeakBins(spectrum []float64) []int {
+	mean, stddev := meanStdDev(spectrum)
+	threshold := mean + noiseFloorStdDevs*stddev
+
+	var bins []int
+	for k := 1; k < len(spectrum)-1; k++ {
+		if spectrum[k] <= threshold {
+			continue
+		}
+		if spectrum[k] < spectrum[k-1] || spectrum[k] < spectrum[k+1] {
+			continue // not a local maximum
+		}
+		bins = append(bins, k)
+	}
+	return bins
+}
+
+// explainedByHarmonic reports whether k is better explained as an overtone
+// of one of the already-accepted fundamentals than as a fundamental in its
+// own right: k is a candidate overtone of f when k is one of f's first
+// numHarmonics multiples, and it is merely a shared factor - not a distinct
+// note - unless its own Harmonic Product Spectrum value strictly dominates
+// f's, meaning k's own multiples explain its energy better than f's do.
+func explainedByHarmonic(k int, fundamentals []int, hps []float64) bool {
+	for _, f := range fundamentals {
+		if r := k / f; f > 0 && k%f == 0 && r >= 2 && r <= numHarmonics {
+			if hps[k] <= hps[f] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func meanStdDev(values []float64) (mean, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean = sum / float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		diff := v - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(values))
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,48 @@
+// Package pitchdetect turns FFT magnitude spectra into musical notes.
+package pitchdetect
+
+import (
+	"fmt"
+	"math"
+)
+
+// Note represents a detected or reference musical pitch.
+type Note struct {
+	Name      string
+	Frequency float64
+	Magnitude float64
+}
+
+// PianoNotes holds the 61 piano key references (C2 through C7) that
+// detected frequencies are snapped to.
+var PianoNotes = generatePianoNotes()
+
+func generatePianoNotes() []Note {
+	notes := make([]Note, 61)
+	noteNames := []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	A4 := 440.0
+	startFreq := A4 * math.Pow(2, -33.0/12.0)
+
+	for i := 0; i < 61; i++ {
+		freq := startFreq * math.Pow(2, float64(i)/12.0)
+		octave := 2 + (i / 12)
+		noteIdx := i % 12
+		name := fmt.Sprintf("%s%d", noteNames[noteIdx], octave)
+		notes[i] = Note{Name: name, Frequency: freq}
+	}
+	return notes
+}
+
+// FindClosestNote returns the PianoNotes entry nearest to freq.
+func FindClosestNote(freq float64) Note {
+	closest := PianoNotes[0]
+	minDiff := math.Abs(freq - closest.Frequency)
+	for _, note := range PianoNotes[1:] {
+		diff := math.Abs(freq - note.Frequency)
+		if diff < minDiff {
+			minDiff = diff
+			closest = note
+		}
+	}
+	return closest
+}
@@ -0,0 +1,123 @@
+package pitchdetect
+
+import "math"
+
+// RefinedPeak is a sub-bin-accurate frequency estimate produced by
+// parabolic interpolation and validated against a narrow harmonic window.
+type RefinedPeak struct {
+	Frequency float64 // interpolated frequency, in Hz
+	Cents     float64 // offset from the nearest PianoNotes entry, in cents
+	SNR       float64 // peak amplitude over the window's local noise floor
+}
+
+// harmonicWindowCents is the half-width searched around each candidate
+// frequency when refining it, in cents, so the window spans 100 cents (1
+// semitone) in all - wide enough to absorb interpolation error but narrow
+// enough to keep adjacent piano keys from polluting each other's noise
+// floor.
+const harmonicWindowCents = 50.0
+
+// minWindowHalfBins is the minimum half-width, in bins, of the noise-floor
+// window around a candidate. Bins have a fixed width in Hz while a
+// semitone's width grows with frequency, so at low frequencies the cents
+// window covers a bin or less, which leaves nothing but the peak's own main
+// lobe to sample - this floors the window so there are always enough bins
+// outside the lobe to estimate a real noise level from.
+const minWindowHalfBins = 4
+
+// minRefinedSNR is K, how many standard deviations above the window's mean
+// amplitude a refined peak must reach to be reported; below this it is
+// treated as noise.
+const minRefinedSNR = 2.0
+
+// ParabolicInterpolate fits a parabola through the log-magnitudes at bins
+// k-1, k and k+1 and returns the vertex offset delta, so k+delta is a
+// sub-bin-accurate estimate of the true peak location.
+func ParabolicInterpolate(magnitudes []float64, k int) float64 {
+	if k <= 0 || k >= len(magnitudes)-1 {
+		return 0
+	}
+	left := math.Log(magnitudes[k-1] + 1e-12)
+	center := math.Log(magnitudes[k] + 1e-12)
+	right := math.Log(magnitudes[k+1] + 1e-12)
+
+	denom := left - 2*center + right
+	if denom == 0 {
+		return 0
+	}
+	return 0.5 * (left - right) / denom
+}
+
+// RefineFrequency refines the coarse bin k to sub-bin accuracy via
+// ParabolicInterpolate, then validates the result against a window spanning
+// +/- harmonicWindowCents around the interpolated frequency (widened to at
+// least minWindowHalfBins on each side): the bin's amplitude must clear the
+// window's mean+minRefinedSNR*stddev noise floor - computed excluding k's
+// own bin, which is the peak being validated, not part of its noise floor -
+// or ok is false.
+func RefineFrequency(magnitudes []float64, freqResolution float64, k int) (peak RefinedPeak, ok bool) {
+	delta := ParabolicInterpolate(magnitudes, k)
+	freq := (float64(k) + delta) * freqResolution
+
+	loBin := int(freq*centsToRatio(-harmonicWindowCents)/freqResolution + 0.5)
+	hiBin := int(freq*centsToRatio(harmonicWindowCents)/freqResolution + 0.5)
+	if k-minWindowHalfBins < loBin {
+		loBin = k - minWindowHalfBins
+	}
+	if k+minWindowHalfBins > hiBin {
+		hiBin = k + minWindowHalfBins
+	}
+	if loBin < 0 {
+		loBin = 0
+	}
+	if hiBin >= len(magnitudes) {
+		hiBin = len(magnitudes) - 1
+	}
+	if loBin >= hiBin {
+		return RefinedPeak{}, false
+	}
+
+	floor := make([]float64, 0, hiBin-loBin)
+	for i := loBin; i <= hiBin; i++ {
+		if i == k {
+			continue
+		}
+		floor = append(floor, magnitudes[i])
+	}
+	if len(floor) == 0 {
+		return RefinedPeak{}, false
+	}
+
+	mean, stddev := meanStdDev(floor)
+	peakAmp := magnitudes[k]
+	if stddev == 0 || peakAmp <= mean+minRefinedSNR*stddev {
+		return RefinedPeak{}, false
+	}
+
+	note := FindClosestNote(freq)
+	cents := 1200 * math.Log2(freq/note.Frequency)
+	snr := peakAmp / (mean + stddev)
+
+	return RefinedPeak{Frequency: freq, Cents: cents, SNR: snr}, true
+}
+
+// centsToRatio converts an offset in cents to a frequency ratio.
+func centsToRatio(cents float64) float64 {
+	return math.Pow(2, cents/1200.0)
+}
+
+// RefineNotes takes the coarse notes DetectNotes finds and refines each to
+// sub-bin accuracy with RefineFrequency, keeping only peaks that clear
+// their local harmonic window's noise floor. This gives frequency
+// precision finer than freqResolution, which matters for low piano notes
+// spaced closer together than a single FFT bin.
+func RefineNotes(magnitudes []float64, freqResolution float64, maxBin int) []RefinedPeak {
+	var peaks []RefinedPeak
+	for _, note := range DetectNotes(magnitudes, freqResolution, maxBin) {
+		k := int(math.Round(note.Frequency / freqResolution))
+		if peak, ok := RefineFrequency(magnitudes, freqResolution, k); ok {
+			peaks = append(peaks, peak)
+		}
+	}
+	return peaks
+}
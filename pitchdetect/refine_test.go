@@ -0,0 +1,50 @@
+package pitchdetect
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+func TestRefineNotes_RecoversKnownFrequency(t *testing.T) {
+	const sampleRate = 44100
+	const frameSize = 2048
+	const targetFreq = 440.0 // A4
+
+	samples := make([]complex128, frameSize)
+	for i := range samples {
+		sec := float64(i) / sampleRate
+		// Window like the real spectrum.Analyzer pipeline does: an
+		// unwindowed (rectangular) frame's slow sidelobe decay biases the
+		// log-parabola interpolation enough to miss the 1 Hz tolerance
+		// below, even though the underlying bin is correctly identified.
+		hann := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(frameSize-1)))
+		samples[i] = complex(math.Sin(2*math.Pi*targetFreq*sec)*hann, 0)
+	}
+
+	freqDomain := fft.FFT(samples)
+	magnitudes := make([]float64, frameSize/2)
+	for i := range magnitudes {
+		magnitudes[i] = cmplx.Abs(freqDomain[i])
+	}
+
+	freqResolution := float64(sampleRate) / float64(frameSize)
+	highFreqLimit := int(2200.0 / freqResolution)
+
+	peaks := RefineNotes(magnitudes, freqResolution, highFreqLimit)
+	if len(peaks) == 0 {
+		t.Fatalf("expected at least one detected peak, got none")
+	}
+
+	best := peaks[0]
+	for _, p := range peaks[1:] {
+		if math.Abs(p.Frequency-targetFreq) < math.Abs(best.Frequency-targetFreq) {
+			best = p
+		}
+	}
+	if math.Abs(best.Frequency-targetFreq) > 1.0 {
+		t.Errorf("refined frequency = %.2f Hz, want within 1 Hz of %.2f Hz", best.Frequency, targetFreq)
+	}
+}
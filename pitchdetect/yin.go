@@ -0,0 +1,95 @@
+package pitchdetect
+
+// yinThreshold is the cumulative mean normalized difference a lag must
+// drop below before it is accepted as the fundamental period.
+const yinThreshold = 0.1
+
+// DetectPitchYIN estimates the fundamental frequency of samples (a single
+// analysis window of a monophonic signal) at sampleRate using the YIN
+// algorithm, which tends to be more robust than FFT peak-picking for low
+// piano notes. clarity is 1 minus the accepted lag's cumulative mean
+// normalized difference - close to 1 for a clean periodic signal, usable
+// as a confidence measure (e.g. to derive a MIDI velocity). It reports
+// ok=false when no lag satisfies yinThreshold, e.g. on silence or very
+// noisy input - callers should fall back to an FFT-based backend in that
+// case.
+func DetectPitchYIN(samples []float64, sampleRate int) (freq, clarity float64, ok bool) {
+	w := len(samples) / 2
+	if w < 2 {
+		return 0, 0, false
+	}
+
+	diff := yinDifference(samples, w)
+	cmnd := yinCumulativeMeanNormalizedDifference(diff)
+
+	tau := yinAbsoluteThreshold(cmnd)
+	if tau == 0 {
+		return 0, 0, false
+	}
+
+	refinedTau := parabolicInterpolateTau(cmnd, tau)
+	return float64(sampleRate) / refinedTau, 1 - cmnd[tau], true
+}
+
+// parabolicInterpolateTau fits a parabola through cmnd at tau-1, tau and
+// tau+1 and returns the vertex location, giving sub-sample period
+// precision instead of the single nearest integer lag.
+func parabolicInterpolateTau(cmnd []float64, tau int) float64 {
+	if tau <= 0 || tau >= len(cmnd)-1 {
+		return float64(tau)
+	}
+	s0, s1, s2 := cmnd[tau-1], cmnd[tau], cmnd[tau+1]
+	denom := s0 - 2*s1 + s2
+	if denom == 0 {
+		return float64(tau)
+	}
+	return float64(tau) + 0.5*(s0-s2)/denom
+}
+
+// yinDifference computes d(tau) = sum((x[i] - x[i+tau])^2) for tau in
+// [0, w], d(0) left at zero since it is never consulted.
+func yinDifference(samples []float64, w int) []float64 {
+	d := make([]float64, w+1)
+	for tau := 1; tau <= w; tau++ {
+		sum := 0.0
+		for i := 0; i < w; i++ {
+			diff := samples[i] - samples[i+tau]
+			sum += diff * diff
+		}
+		d[tau] = sum
+	}
+	return d
+}
+
+// yinCumulativeMeanNormalizedDifference computes d'(tau) = d(tau) /
+// ((1/tau) * sum(d(j) for j=1..tau)), with d'(0) fixed at 1 so that tau=0
+// never looks like a valid period.
+func yinCumulativeMeanNormalizedDifference(d []float64) []float64 {
+	cmnd := make([]float64, len(d))
+	cmnd[0] = 1
+	runningSum := 0.0
+	for tau := 1; tau < len(d); tau++ {
+		runningSum += d[tau]
+		if runningSum == 0 {
+			cmnd[tau] = 1
+		} else {
+			cmnd[tau] = d[tau] * float64(tau) / runningSum
+		}
+	}
+	return cmnd
+}
+
+// yinAbsoluteThreshold returns the smallest tau >= 2 where cmnd drops
+// below yinThreshold and is a local minimum, or 0 if none does.
+func yinAbsoluteThreshold(cmnd []float64) int {
+	for tau := 2; tau < len(cmnd); tau++ {
+		if cmnd[tau] >= yinThreshold {
+			continue
+		}
+		for tau+1 < len(cmnd) && cmnd[tau+1] < cmnd[tau] {
+			tau++
+		}
+		return tau
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package pitchdetect
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetectPitchYIN_RecoversKnownFrequency(t *testing.T) {
+	const sampleRate = 44100
+	const targetFreq = 220.0 // A3
+	const numSamples = 4096
+
+	samples := make([]float64, numSamples)
+	for i := range samples {
+		sec := float64(i) / sampleRate
+		samples[i] = math.Sin(2 * math.Pi * targetFreq * sec)
+	}
+
+	freq, _, ok := DetectPitchYIN(samples, sampleRate)
+	if !ok {
+		t.Fatalf("expected YIN to detect a pitch, got ok=false")
+	}
+	if math.Abs(freq-targetFreq) > 1.0 {
+		t.Errorf("detected frequency = %.2f Hz, want within 1 Hz of %.2f Hz", freq, targetFreq)
+	}
+}
+
+func TestDetectPitchYIN_SilenceFallsBack(t *testing.T) {
+	samples := make([]float64, 4096)
+	if _, _, ok := DetectPitchYIN(samples, 44100); ok {
+		t.Errorf("expected silence to report ok=false so callers fall back to FFT")
+	}
+}
@@ -0,0 +1,87 @@
+// Package spectrum implements a windowed, overlapping short-time Fourier
+// transform pipeline: callers feed it arbitrarily-sized chunks of audio
+// and it emits one magnitude spectrum per hop. Analyzer reuses its own
+// ring buffer, window and magnitude-spectrum scratch space across calls,
+// though fft.FFT itself still allocates its output spectrum per call - see
+// BenchmarkAnalyzer_Write for the actual allocation count.
+package spectrum
+
+import (
+	"math/cmplx"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+// Window names a window function applied to each frame before FFT, to
+// suppress the spectral leakage a rectangular (unwindowed) frame produces.
+type Window int
+
+const (
+	Hann Window = iota
+	BlackmanHarris
+)
+
+// Analyzer owns a ring buffer of incoming samples, precomputed window
+// coefficients, and scratch buffers for the FFT input and magnitude
+// spectrum, so that Write itself does not allocate once steady state is
+// reached - the FFT call it makes per frame still does (see package doc).
+type Analyzer struct {
+	frameSize int
+	hopSize   int
+
+	ring      []float32
+	ringPos   int // index of the oldest sample / next write position
+	available int // total samples written so far
+
+	frameIndex int // count of frames emitted so far
+
+	window     []float64
+	complexBuf []complex128
+	magnitudes []float64
+}
+
+// NewAnalyzer builds an Analyzer for frameSize-sample frames, emitting a
+// new frame every hopSize samples (e.g. frameSize/4 for 75% overlap, or
+// frameSize/2 for 50%), windowed with windowFn.
+func NewAnalyzer(frameSize, hopSize int, windowFn Window) *Analyzer {
+	return &Analyzer{
+		frameSize:  frameSize,
+		hopSize:    hopSize,
+		ring:       make([]float32, frameSize),
+		window:     makeWindow(frameSize, windowFn),
+		complexBuf: make([]complex128, frameSize),
+		magnitudes: make([]float64, frameSize/2),
+	}
+}
+
+// Write appends samples to the ring buffer. Each time a full hop's worth
+// of new samples has accumulated on top of a complete frame, it calls
+// process with the windowed magnitude spectrum and that frame's starting
+// sample offset in the overall stream. process must not retain the slice
+// it is given, since Analyzer reuses it on the next call.
+func (a *Analyzer) Write(samples []float32, process func(magnitudes []float64, frameOffset int)) {
+	for _, s := range samples {
+		a.ring[a.ringPos] = s
+		a.ringPos = (a.ringPos + 1) % a.frameSize
+		a.available++
+
+		if a.available >= a.frameSize && (a.available-a.frameSize)%a.hopSize == 0 {
+			a.analyzeFrame(process)
+		}
+	}
+}
+
+func (a *Analyzer) analyzeFrame(process func(magnitudes []float64, frameOffset int)) {
+	for i := 0; i < a.frameSize; i++ {
+		idx := (a.ringPos + i) % a.frameSize
+		a.complexBuf[i] = complex(float64(a.ring[idx])*a.window[i], 0)
+	}
+
+	freqDomain := fft.FFT(a.complexBuf)
+	for i := range a.magnitudes {
+		a.magnitudes[i] = cmplx.Abs(freqDomain[i])
+	}
+
+	process(a.magnitudes, a.frameIndex*a.hopSize)
+	a.frameIndex++
+}
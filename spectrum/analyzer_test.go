@@ -0,0 +1,24 @@
+package spectrum
+
+import "testing"
+
+// BenchmarkAnalyzer_Write reports the real allocation count for Write,
+// including whatever fft.FFT allocates internally - Analyzer's own buffers
+// are reused, but fft.FFT returns a freshly allocated spectrum on every
+// call, so the allocs/op that b.ReportAllocs() reports here is expected to
+// be nonzero. Each op writes frameSize samples at a hop of frameSize/2, so
+// once the ring buffer is full one op covers two frames.
+func BenchmarkAnalyzer_Write(b *testing.B) {
+	const frameSize = 2048
+	a := NewAnalyzer(frameSize, frameSize/2, Hann)
+	samples := make([]float32, frameSize)
+	for i := range samples {
+		samples[i] = float32(i%100) / 100
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Write(samples, func(magnitudes []float64, frameOffset int) {})
+	}
+}
@@ -0,0 +1,108 @@
+// Package transcribe decodes recorded audio files and runs the same
+// pitch-detection pipeline used for live input over them, for offline
+// transcription.
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-audio/wav"
+	"github.com/mewkiz/flac"
+)
+
+// Decode reads an audio file and returns its samples as float32 in
+// [-1, 1] alongside its native sample rate. The format is chosen by file
+// extension: .wav or .flac.
+func Decode(path string) (samples []float32, sampleRate int, err error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".wav":
+		return decodeWAV(path)
+	case ".flac":
+		return decodeFLAC(path)
+	default:
+		return nil, 0, fmt.Errorf("unsupported audio format %q", ext)
+	}
+}
+
+func decodeWAV(path string) ([]float32, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	decoder := wav.NewDecoder(f)
+	buf, err := decoder.FullPCMBuffer()
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	channels := buf.Format.NumChannels
+	if channels < 1 {
+		channels = 1
+	}
+	maxAmp := float32(int32(1) << uint(buf.SourceBitDepth-1))
+	samples := make([]float32, len(buf.Data)/channels)
+	for i := range samples {
+		var sum int64
+		for c := 0; c < channels; c++ {
+			sum += int64(buf.Data[i*channels+c])
+		}
+		samples[i] = float32(sum/int64(channels)) / maxAmp
+	}
+	return samples, buf.Format.SampleRate, nil
+}
+
+func decodeFLAC(path string) ([]float32, int, error) {
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	defer stream.Close()
+
+	maxAmp := float32(int32(1) << uint(stream.Info.BitsPerSample-1))
+	var samples []float32
+	for {
+		frame, err := stream.ParseNext()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("decoding %s: %w", path, err)
+		}
+		channels := len(frame.Subframes)
+		for i := 0; i < int(frame.BlockSize); i++ {
+			var sum int64
+			for _, sub := range frame.Subframes {
+				sum += int64(sub.Samples[i])
+			}
+			samples = append(samples, float32(sum/int64(channels))/maxAmp)
+		}
+	}
+	return samples, int(stream.Info.SampleRate), nil
+}
+
+// Resample linearly resamples samples from srcRate to dstRate.
+func Resample(samples []float32, srcRate, dstRate int) []float32 {
+	if srcRate == dstRate || len(samples) == 0 {
+		return samples
+	}
+	ratio := float64(dstRate) / float64(srcRate)
+	out := make([]float32, int(float64(len(samples))*ratio))
+	for i := range out {
+		srcPos := float64(i) / ratio
+		lo := int(srcPos)
+		if lo+1 >= len(samples) {
+			out[i] = samples[len(samples)-1]
+			continue
+		}
+		frac := float32(srcPos - float64(lo))
+		out[i] = samples[lo]*(1-frac) + samples[lo+1]*frac
+	}
+	return out
+}
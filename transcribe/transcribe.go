@@ -0,0 +1,32 @@
+package transcribe
+
+import (
+	"github.com/Kazzyman/grockMusic/pitchdetect"
+	"github.com/Kazzyman/grockMusic/spectrum"
+)
+
+// Event is one detection frame's worth of transcription output.
+type Event struct {
+	FrameOffset int // sample index of the frame's first sample
+	Peaks       []pitchdetect.RefinedPeak
+}
+
+// Transcribe runs the same windowed, overlapping FFT/HPS detection
+// pipeline used for live input over samples, hopping frameSize/2 samples
+// between analysis frames, and returns one Event per frame with at least
+// one detected peak.
+func Transcribe(samples []float32, sampleRate, frameSize int) []Event {
+	freqResolution := float64(sampleRate) / float64(frameSize)
+	highFreqLimit := int(2200.0 / freqResolution)
+
+	var events []Event
+	analyzer := spectrum.NewAnalyzer(frameSize, frameSize/2, spectrum.Hann)
+	analyzer.Write(samples, func(magnitudes []float64, frameOffset int) {
+		peaks := pitchdetect.RefineNotes(magnitudes, freqResolution, highFreqLimit)
+		if len(peaks) == 0 {
+			return
+		}
+		events = append(events, Event{FrameOffset: frameOffset, Peaks: peaks})
+	})
+	return events
+}
@@ -0,0 +1,125 @@
+package transcribe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Kazzyman/grockMusic/midiout"
+	"github.com/Kazzyman/grockMusic/pitchdetect"
+)
+
+// ticksPerQuarter and microsecondsPerQuarter fix the MIDI file's clock at
+// 480 ticks per quarter note, 120bpm.
+const (
+	ticksPerQuarter        = 480
+	microsecondsPerQuarter = 500000
+	noteDurationTicks      = ticksPerQuarter / 4
+)
+
+// WriteResults writes events to path as CSV or a Standard MIDI File,
+// chosen from path's extension (.csv or .mid/.midi).
+func WriteResults(path string, events []Event, sampleRate int) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return writeCSV(path, events, sampleRate)
+	case ".mid", ".midi":
+		return writeMIDI(path, events, sampleRate)
+	default:
+		return fmt.Errorf("unsupported output format %q (use .csv or .mid)", ext)
+	}
+}
+
+func writeCSV(path string, events []Event, sampleRate int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "frame_offset,seconds,note,frequency_hz,snr")
+	for _, ev := range events {
+		seconds := float64(ev.FrameOffset) / float64(sampleRate)
+		for _, peak := range ev.Peaks {
+			note := pitchdetect.FindClosestNote(peak.Frequency)
+			fmt.Fprintf(f, "%d,%.4f,%s,%.2f,%.2f\n", ev.FrameOffset, seconds, note.Name, peak.Frequency, peak.SNR)
+		}
+	}
+	return nil
+}
+
+type midiEvent struct {
+	tick     uint32
+	status   byte
+	note     byte
+	velocity byte
+}
+
+func writeMIDI(path string, events []Event, sampleRate int) error {
+	var timed []midiEvent
+	for _, ev := range events {
+		seconds := float64(ev.FrameOffset) / float64(sampleRate)
+		tick := uint32(seconds * ticksPerQuarter * 1e6 / microsecondsPerQuarter)
+		for _, peak := range ev.Peaks {
+			note := pitchdetect.FindClosestNote(peak.Frequency)
+			midiNote := byte(midiout.FrequencyToMIDI(note.Frequency))
+			velocity := byte(midiout.VelocityFromSNR(peak.SNR))
+			timed = append(timed, midiEvent{tick: tick, status: 0x90, note: midiNote, velocity: velocity})
+			timed = append(timed, midiEvent{tick: tick + noteDurationTicks, status: 0x80, note: midiNote, velocity: 0})
+		}
+	}
+	sort.Slice(timed, func(i, j int) bool { return timed[i].tick < timed[j].tick })
+
+	var track []byte
+	prevTick := uint32(0)
+	for _, e := range timed {
+		track = append(track, encodeVarLen(e.tick-prevTick)...)
+		track = append(track, e.status, e.note, e.velocity)
+		prevTick = e.tick
+	}
+	track = append(track, 0x00, 0xFF, 0x2F, 0x00) // end of track
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer f.Close()
+
+	header := []byte{
+		0x00, 0x00, // format 0
+		0x00, 0x01, // one track
+		byte(ticksPerQuarter >> 8), byte(ticksPerQuarter & 0xFF),
+	}
+	if err := writeChunk(f, "MThd", header); err != nil {
+		return err
+	}
+	return writeChunk(f, "MTrk", track)
+}
+
+func writeChunk(f *os.File, id string, data []byte) error {
+	length := []byte{
+		byte(len(data) >> 24), byte(len(data) >> 16),
+		byte(len(data) >> 8), byte(len(data)),
+	}
+	if _, err := f.WriteString(id); err != nil {
+		return err
+	}
+	if _, err := f.Write(length); err != nil {
+		return err
+	}
+	_, err := f.Write(data)
+	return err
+}
+
+// encodeVarLen encodes v as a MIDI variable-length quantity.
+func encodeVarLen(v uint32) []byte {
+	buf := []byte{byte(v & 0x7F)}
+	v >>= 7
+	for v > 0 {
+		buf = append([]byte{byte(v&0x7F) | 0x80}, buf...)
+		v >>= 7
+	}
+	return buf
+}